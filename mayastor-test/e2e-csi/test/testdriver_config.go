@@ -0,0 +1,177 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/kubernetes/test/e2e/framework"
+	"k8s.io/kubernetes/test/e2e/storage/testpatterns"
+	"k8s.io/kubernetes/test/e2e/storage/testsuites"
+)
+
+// testDriverFile points at a DriverDefinition describing the mayastor
+// topology (protocol, replica count, fsType, capabilities) the suite should
+// exercise. Mirrors the upstream -storage.testdriver flag used to
+// parameterize the external storage driver, so CI can run the same compiled
+// suite against nvmf/iscsi, repl 1/2/3 and thin/thick topologies without a
+// rebuild. When unset, InitMayastorDriver falls back to the hard-coded
+// nvmf/repl=1 driver built by initMayastorDriver.
+var testDriverFile = flag.String("storage.mayastor.testdriver", "",
+	"path to a YAML or JSON DriverDefinition file describing the mayastor topology to test")
+
+// StorageClassParameters is a named, reusable StorageClass parameter set plus
+// the fsType it applies to, so a single DriverDefinition can cover more than
+// one mayastor topology (nvmf/iscsi, repl 1/2/3, thin/thick).
+type StorageClassParameters struct {
+	// Name identifies this parameter set within the file, for diagnostics only.
+	Name string `json:"name"`
+	// FsType is the filesystem this parameter set is selected for.
+	// The empty string matches the driver's default fsType.
+	FsType string `json:"fsType"`
+	// Parameters are passed straight through to the StorageClass.
+	Parameters map[string]string `json:"parameters"`
+}
+
+// SizeRange is the decodable equivalent of testpatterns.SizeRange.
+type SizeRange struct {
+	Min string `json:"min"`
+	Max string `json:"max"`
+}
+
+// DriverDefinition is the on-disk shape of a mayastor e2e driver: the
+// provisioner name, manifests to deploy it, advertised capabilities and the
+// StorageClass parameter sets the suite should cycle through.
+type DriverDefinition struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// DriverName is the name the driver registers with the framework under,
+	// mirroring mayastorDriver.driverInfo.Name.
+	DriverName string `json:"driverName"`
+	// Manifests lists the YAML manifests (relative to the e2e manifests dir)
+	// used to deploy this topology.
+	Manifests []string `json:"manifests"`
+	// SupportedFsType enumerates the fsTypes this topology can be exercised
+	// with, e.g. "", "ext4", "xfs".
+	SupportedFsType []string `json:"supportedFsType"`
+	// SupportedSizeRange bounds the volume sizes the suite will generate.
+	SupportedSizeRange SizeRange `json:"supportedSizeRange"`
+	// Capabilities mirrors testsuites.Capability by name, e.g. "persistence",
+	// "block", "exec", "multipods".
+	Capabilities map[string]bool `json:"capabilities"`
+	// StorageClasses is the set of named parameter sets
+	// GetDynamicProvisionStorageClass selects between, keyed by fsType.
+	StorageClasses []StorageClassParameters `json:"storageClasses"`
+}
+
+// DeepCopyObject implements runtime.Object so DriverDefinition can be decoded
+// through the universal decoder like any other typed config object.
+func (d *DriverDefinition) DeepCopyObject() runtime.Object {
+	out := &DriverDefinition{
+		TypeMeta:           d.TypeMeta,
+		DriverName:         d.DriverName,
+		SupportedSizeRange: d.SupportedSizeRange,
+		Manifests:          append([]string(nil), d.Manifests...),
+		SupportedFsType:    append([]string(nil), d.SupportedFsType...),
+		StorageClasses:     append([]StorageClassParameters(nil), d.StorageClasses...),
+	}
+	if d.Capabilities != nil {
+		out.Capabilities = make(map[string]bool, len(d.Capabilities))
+		for k, v := range d.Capabilities {
+			out.Capabilities[k] = v
+		}
+	}
+	return out
+}
+
+var (
+	driverGroupVersion = schema.GroupVersion{Group: "mayastor.openebs.io", Version: "v1"}
+	driverScheme       = runtime.NewScheme()
+	// driverCodecs decodes DriverDefinition files with EnableStrict so a
+	// typo'd field in a CI config fails loudly instead of silently zeroing out.
+	driverCodecs = serializer.NewCodecFactory(driverScheme, serializer.EnableStrict)
+)
+
+func init() {
+	driverScheme.AddKnownTypes(driverGroupVersion, &DriverDefinition{})
+	metav1.AddToGroupVersion(driverScheme, driverGroupVersion)
+}
+
+// capabilityNames maps the lower-case capability names accepted in a
+// DriverDefinition file to the testsuites.Capability constants they select.
+var capabilityNames = map[string]testsuites.Capability{
+	"persistence":         testsuites.CapPersistence,
+	"block":               testsuites.CapBlock,
+	"exec":                testsuites.CapExec,
+	"multipods":           testsuites.CapMultiPODs,
+	"snapshotdatasource":  testsuites.CapSnapshotDataSource,
+	"controllerexpansion": testsuites.CapControllerExpansion,
+}
+
+// loadDriverDefinition reads and strictly decodes a DriverDefinition from
+// filename, rejecting unknown fields.
+func loadDriverDefinition(filename string) (*DriverDefinition, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read test driver file %q: %v", filename, err)
+	}
+
+	jsonData, err := yaml.ToJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert test driver file %q to JSON: %v", filename, err)
+	}
+
+	driver := &DriverDefinition{}
+	if err := runtime.DecodeInto(driverCodecs.UniversalDecoder(driverGroupVersion), jsonData, driver); err != nil {
+		return nil, fmt.Errorf("failed to parse test driver file %q: %v", filename, err)
+	}
+
+	return driver, nil
+}
+
+// initMayastorDriverFromDefinition builds a mayastorDriver from a
+// DriverDefinition loaded via -storage.mayastor.testdriver.
+func initMayastorDriverFromDefinition(driver *DriverDefinition) testsuites.TestDriver {
+	capabilities := make(map[testsuites.Capability]bool, len(driver.Capabilities))
+	for name, enabled := range driver.Capabilities {
+		capability, ok := capabilityNames[name]
+		if !ok {
+			framework.Failf("test driver file declares unknown capability %q", name)
+		}
+		capabilities[capability] = enabled
+	}
+
+	return &mayastorDriver{
+		driverInfo: testsuites.DriverInfo{
+			Name:            driver.DriverName,
+			MaxFileSize:     testpatterns.FileSizeLarge,
+			SupportedFsType: sets.NewString(driver.SupportedFsType...),
+			SupportedSizeRange: testpatterns.SizeRange{
+				Min: driver.SupportedSizeRange.Min,
+				Max: driver.SupportedSizeRange.Max,
+			},
+			Capabilities: capabilities,
+		},
+		manifests:      driver.Manifests,
+		storageClasses: driver.StorageClasses,
+	}
+}
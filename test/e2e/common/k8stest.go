@@ -0,0 +1,82 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package common holds test helpers shared across the mayastor e2e Ginkgo
+// specs: cluster setup/teardown, StorageClass management and the kubectl/
+// client-go plumbing individual test suites build on.
+package common
+
+import (
+	"os"
+
+	. "github.com/onsi/gomega"
+
+	snapshot "github.com/kubernetes-csi/external-snapshotter/client/v4/clientset/versioned"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// gClientSet is the client-go clientset shared by every helper in this
+// package, populated by SetupTestEnv.
+var gClientSet *kubernetes.Clientset
+
+// gSnapshotClientSet is the external-snapshotter clientset used by
+// VolumeSnapshot/VolumeSnapshotClass helpers, populated by SetupTestEnv.
+var gSnapshotClientSet *snapshot.Clientset
+
+// kubeconfigPath returns the kubeconfig path used to build the test client,
+// honouring KUBECONFIG like kubectl does and defaulting to ~/.kube/config.
+func kubeconfigPath() string {
+	if path := os.Getenv("KUBECONFIG"); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	Expect(err).ToNot(HaveOccurred())
+	return home + "/.kube/config"
+}
+
+// SetupTestEnv builds the shared clientset used by every e2e spec. Ginkgo
+// BeforeSuite hooks call this once per suite run.
+func SetupTestEnv() {
+	restConfig, err := clientcmd.BuildConfigFromFlags("", kubeconfigPath())
+	Expect(err).ToNot(HaveOccurred())
+
+	gClientSet, err = kubernetes.NewForConfig(restConfig)
+	Expect(err).ToNot(HaveOccurred())
+
+	gSnapshotClientSet, err = snapshot.NewForConfig(restConfig)
+	Expect(err).ToNot(HaveOccurred())
+}
+
+// TeardownTestEnv drops the clientsets SetupTestEnv built, so any later use
+// through MustClientSet or MustSnapshotClientSet fails the spec. AfterSuite
+// hooks call this once per suite run.
+func TeardownTestEnv() {
+	gClientSet = nil
+	gSnapshotClientSet = nil
+}
+
+// MustClientSet returns the clientset built by SetupTestEnv, failing the spec
+// if it has not been called yet.
+func MustClientSet() *kubernetes.Clientset {
+	Expect(gClientSet).ToNot(BeNil(), "common.SetupTestEnv must run before the test clientset is used")
+	return gClientSet
+}
+
+// MustSnapshotClientSet returns the external-snapshotter clientset built by
+// SetupTestEnv, failing the spec if it has not been called yet.
+func MustSnapshotClientSet() *snapshot.Clientset {
+	Expect(gSnapshotClientSet).ToNot(BeNil(), "common.SetupTestEnv must run before the snapshot clientset is used")
+	return gSnapshotClientSet
+}
@@ -0,0 +1,54 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// MkPvc creates pvcName against scName in namespace, requesting size.
+func MkPvc(pvcName string, scName string, size string, namespace string) {
+	quantity := resourceQuantity(size)
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: pvcName},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			StorageClassName: &scName,
+			AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: quantity},
+			},
+		},
+	}
+
+	_, err := MustClientSet().CoreV1().PersistentVolumeClaims(namespace).Create(context.TODO(), pvc, metav1.CreateOptions{})
+	Expect(err).ToNot(HaveOccurred(), "failed to create pvc %s", pvcName)
+}
+
+// RmPvc deletes the PVC created by MkPvc.
+func RmPvc(pvcName string, namespace string) {
+	err := MustClientSet().CoreV1().PersistentVolumeClaims(namespace).Delete(context.TODO(), pvcName, metav1.DeleteOptions{})
+	Expect(err).ToNot(HaveOccurred(), "failed to delete pvc %s", pvcName)
+}
+
+// PvcPhase returns the current phase of pvcName.
+func PvcPhase(pvcName string, namespace string) corev1.PersistentVolumeClaimPhase {
+	pvc, err := MustClientSet().CoreV1().PersistentVolumeClaims(namespace).Get(context.TODO(), pvcName, metav1.GetOptions{})
+	Expect(err).ToNot(HaveOccurred(), "failed to get pvc %s", pvcName)
+	return pvc.Status.Phase
+}
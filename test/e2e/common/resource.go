@@ -0,0 +1,29 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	. "github.com/onsi/gomega"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// resourceQuantity parses a quantity string such as "1Gi", failing the spec
+// on a malformed literal rather than propagating a parse error everywhere a
+// PVC size is built.
+func resourceQuantity(s string) resource.Quantity {
+	q, err := resource.ParseQuantity(s)
+	Expect(err).ToNot(HaveOccurred(), "invalid resource quantity %q", s)
+	return q
+}
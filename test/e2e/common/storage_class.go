@@ -0,0 +1,156 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+	"strconv"
+
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// MayastorProvisioner is the CSI provisioner name mayastor registers under.
+const MayastorProvisioner = "io.openebs.csi-mayastor"
+
+// StorageClassBuilder builds a mayastor StorageClass one setting at a time,
+// so a spec can parameterize exactly the fields it cares about (reclaim
+// policy, binding mode, volume expansion, topology restriction, arbitrary
+// provisioner parameters) per It, instead of every spec sharing the one
+// hard-coded shape MkStorageClass used to produce.
+type StorageClassBuilder struct {
+	name                 string
+	provisioner          string
+	parameters           map[string]string
+	reclaimPolicy        corev1.PersistentVolumeReclaimPolicy
+	bindingMode          storagev1.VolumeBindingMode
+	allowVolumeExpansion bool
+	allowedTopologyNode  string
+}
+
+// NewStorageClassBuilder seeds a StorageClassBuilder with the defaults every
+// existing spec relied on: Delete reclaim, Immediate binding, no expansion,
+// no topology restriction.
+func NewStorageClassBuilder(name string, provisioner string) *StorageClassBuilder {
+	return &StorageClassBuilder{
+		name:          name,
+		provisioner:   provisioner,
+		parameters:    map[string]string{},
+		reclaimPolicy: corev1.PersistentVolumeReclaimDelete,
+		bindingMode:   storagev1.VolumeBindingImmediate,
+	}
+}
+
+// WithReplicas sets the "repl" provisioner parameter.
+func (b *StorageClassBuilder) WithReplicas(replicas int) *StorageClassBuilder {
+	b.parameters["repl"] = strconv.Itoa(replicas)
+	return b
+}
+
+// WithProtocol sets the "protocol" provisioner parameter (nvmf|iscsi).
+func (b *StorageClassBuilder) WithProtocol(protocol string) *StorageClassBuilder {
+	b.parameters["protocol"] = protocol
+	return b
+}
+
+// WithParameter sets an arbitrary provisioner parameter, e.g. "ioTimeout" or
+// "local".
+func (b *StorageClassBuilder) WithParameter(key string, value string) *StorageClassBuilder {
+	b.parameters[key] = value
+	return b
+}
+
+// WithReclaimPolicy overrides the default Delete reclaim policy.
+func (b *StorageClassBuilder) WithReclaimPolicy(policy corev1.PersistentVolumeReclaimPolicy) *StorageClassBuilder {
+	b.reclaimPolicy = policy
+	return b
+}
+
+// WithBindingMode overrides the default Immediate binding mode, e.g. to
+// WaitForFirstConsumer.
+func (b *StorageClassBuilder) WithBindingMode(mode storagev1.VolumeBindingMode) *StorageClassBuilder {
+	b.bindingMode = mode
+	return b
+}
+
+// WithVolumeExpansion toggles AllowVolumeExpansion.
+func (b *StorageClassBuilder) WithVolumeExpansion(allow bool) *StorageClassBuilder {
+	b.allowVolumeExpansion = allow
+	return b
+}
+
+// WithAllowedTopologyNode restricts provisioning to the mayastor node with
+// the given kubernetes.io/hostname label.
+func (b *StorageClassBuilder) WithAllowedTopologyNode(nodeName string) *StorageClassBuilder {
+	b.allowedTopologyNode = nodeName
+	return b
+}
+
+// Build returns the StorageClass described by the builder without creating it.
+func (b *StorageClassBuilder) Build() *storagev1.StorageClass {
+	var allowedTopologies []corev1.TopologySelectorTerm
+	if b.allowedTopologyNode != "" {
+		allowedTopologies = []corev1.TopologySelectorTerm{
+			{
+				MatchLabelExpressions: []corev1.TopologySelectorLabelRequirement{
+					{Key: "kubernetes.io/hostname", Values: []string{b.allowedTopologyNode}},
+				},
+			},
+		}
+	}
+
+	reclaimPolicy := b.reclaimPolicy
+	bindingMode := b.bindingMode
+	allowVolumeExpansion := b.allowVolumeExpansion
+
+	return &storagev1.StorageClass{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: b.name,
+		},
+		Provisioner:          b.provisioner,
+		Parameters:           b.parameters,
+		ReclaimPolicy:        &reclaimPolicy,
+		VolumeBindingMode:    &bindingMode,
+		AllowedTopologies:    allowedTopologies,
+		AllowVolumeExpansion: &allowVolumeExpansion,
+	}
+}
+
+// Create builds and creates the StorageClass, failing the spec on error.
+func (b *StorageClassBuilder) Create() {
+	_, err := MustClientSet().StorageV1().StorageClasses().Create(context.TODO(), b.Build(), metav1.CreateOptions{})
+	Expect(err).ToNot(HaveOccurred(), "failed to create storage class %s", b.name)
+}
+
+// MkStorageClass creates a StorageClass named scName provisioned by mayastor
+// with the given replica count and protocol (nvmf|iscsi), Delete reclaim,
+// Immediate binding and no expansion - the common case most specs need.
+// Specs that need to vary reclaim policy, binding mode, volume expansion or
+// topology should build their own StorageClassBuilder instead.
+func MkStorageClass(scName string, replicas int, protocol string, provisioner string) {
+	NewStorageClassBuilder(scName, provisioner).
+		WithReplicas(replicas).
+		WithProtocol(protocol).
+		Create()
+}
+
+// RmStorageClass deletes the StorageClass created by MkStorageClass or a
+// StorageClassBuilder.
+func RmStorageClass(scName string) {
+	err := MustClientSet().StorageV1().StorageClasses().Delete(context.TODO(), scName, metav1.DeleteOptions{})
+	Expect(err).ToNot(HaveOccurred(), "failed to delete storage class %s", scName)
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"context"
+
+	. "github.com/onsi/gomega"
+
+	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v4/apis/volumesnapshot/v1"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// MkVolumeSnapshotClass creates a VolumeSnapshotClass named vscName bound to
+// the mayastor CSI driver, as used by specs that snapshot mayastor PVCs.
+func MkVolumeSnapshotClass(vscName string) {
+	deletionPolicy := snapshotv1.VolumeSnapshotContentDelete
+	vsc := &snapshotv1.VolumeSnapshotClass{
+		ObjectMeta:     metav1.ObjectMeta{Name: vscName},
+		Driver:         MayastorProvisioner,
+		DeletionPolicy: deletionPolicy,
+	}
+
+	_, err := MustSnapshotClientSet().SnapshotV1().VolumeSnapshotClasses().Create(context.TODO(), vsc, metav1.CreateOptions{})
+	Expect(err).ToNot(HaveOccurred(), "failed to create volume snapshot class %s", vscName)
+}
+
+// RmVolumeSnapshotClass deletes the VolumeSnapshotClass created by MkVolumeSnapshotClass.
+func RmVolumeSnapshotClass(vscName string) {
+	err := MustSnapshotClientSet().SnapshotV1().VolumeSnapshotClasses().Delete(context.TODO(), vscName, metav1.DeleteOptions{})
+	Expect(err).ToNot(HaveOccurred(), "failed to delete volume snapshot class %s", vscName)
+}
+
+// MkVolumeSnapshot creates a VolumeSnapshot named snapName of pvcName using
+// vscName, returning once the request has been accepted by the API server;
+// callers wait for ReadyToUse separately since snapshot creation is async.
+func MkVolumeSnapshot(snapName string, pvcName string, vscName string, namespace string) {
+	snap := &snapshotv1.VolumeSnapshot{
+		ObjectMeta: metav1.ObjectMeta{Name: snapName},
+		Spec: snapshotv1.VolumeSnapshotSpec{
+			VolumeSnapshotClassName: &vscName,
+			Source: snapshotv1.VolumeSnapshotSource{
+				PersistentVolumeClaimName: &pvcName,
+			},
+		},
+	}
+
+	_, err := MustSnapshotClientSet().SnapshotV1().VolumeSnapshots(namespace).Create(context.TODO(), snap, metav1.CreateOptions{})
+	Expect(err).ToNot(HaveOccurred(), "failed to create volume snapshot %s", snapName)
+}
+
+// RmVolumeSnapshot deletes the VolumeSnapshot created by MkVolumeSnapshot.
+func RmVolumeSnapshot(snapName string, namespace string) {
+	err := MustSnapshotClientSet().SnapshotV1().VolumeSnapshots(namespace).Delete(context.TODO(), snapName, metav1.DeleteOptions{})
+	Expect(err).ToNot(HaveOccurred(), "failed to delete volume snapshot %s", snapName)
+}
+
+// VolumeSnapshotReady reports whether snapName has become ReadyToUse.
+func VolumeSnapshotReady(snapName string, namespace string) bool {
+	snap, err := MustSnapshotClientSet().SnapshotV1().VolumeSnapshots(namespace).Get(context.TODO(), snapName, metav1.GetOptions{})
+	Expect(err).ToNot(HaveOccurred(), "failed to get volume snapshot %s", snapName)
+	return snap.Status != nil && snap.Status.ReadyToUse != nil && *snap.Status.ReadyToUse
+}
+
+// MkPvcFromSnapshot creates pvcName restored from snapName against scName,
+// the same shape MkStorageClass-provisioned PVCs use elsewhere in the suite.
+func MkPvcFromSnapshot(pvcName string, scName string, snapName string, size string, namespace string) {
+	apiGroup := "snapshot.storage.k8s.io"
+	quantity := resourceQuantity(size)
+
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: pvcName},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			StorageClassName: &scName,
+			AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			DataSource: &corev1.TypedLocalObjectReference{
+				APIGroup: &apiGroup,
+				Kind:     "VolumeSnapshot",
+				Name:     snapName,
+			},
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: quantity},
+			},
+		},
+	}
+
+	_, err := MustClientSet().CoreV1().PersistentVolumeClaims(namespace).Create(context.TODO(), pvc, metav1.CreateOptions{})
+	Expect(err).ToNot(HaveOccurred(), "failed to create pvc %s restored from snapshot %s", pvcName, snapName)
+}
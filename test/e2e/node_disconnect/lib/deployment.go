@@ -0,0 +1,92 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"context"
+
+	"e2e-basic/common"
+
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SetupDeployment is Setup, but backs the fio fixture with a single-replica
+// Deployment instead of a bare pod. Specs that force-delete the workload's
+// pod as part of the fault they inject (NodeShutdownTest's out-of-service
+// taint) need a controller to recreate it elsewhere; nothing ever recreates
+// a bare Pod once it's gone.
+func SetupDeployment(pvcName string, scName string, imageName string) DisconnectEnv {
+	pvc := &v1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: pvcName},
+		Spec: v1.PersistentVolumeClaimSpec{
+			StorageClassName: &scName,
+			AccessModes:      []v1.PersistentVolumeAccessMode{v1.ReadWriteOnce},
+			Resources: v1.ResourceRequirements{
+				Requests: v1.ResourceList{v1.ResourceStorage: resourceQuantity("1Gi")},
+			},
+		},
+	}
+	_, err := common.MustClientSet().CoreV1().PersistentVolumeClaims(namespace()).Create(context.TODO(), pvc, metav1.CreateOptions{})
+	Expect(err).ToNot(HaveOccurred(), "failed to create pvc %s", pvcName)
+
+	env := AttachDeployment(imageName, pvcName, imageName)
+	env.ScName = scName
+	return env
+}
+
+// AttachDeployment rolls out a single-replica Deployment named deployName
+// running imageName against an already-provisioned filesystem-mode pvcName,
+// waiting for its pod to become Running and recording which node it landed
+// on.
+func AttachDeployment(deployName string, pvcName string, imageName string) DisconnectEnv {
+	podLabels := map[string]string{"app": deployName}
+
+	pod := fioPod(deployName, pvcName, imageName, common.VolumeModeFilesystem)
+	pod.Spec.RestartPolicy = v1.RestartPolicyAlways
+
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: deployName},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: replicas(1),
+			Selector: &metav1.LabelSelector{MatchLabels: podLabels},
+			Template: v1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: podLabels},
+				Spec:       pod.Spec,
+			},
+		},
+	}
+	_, err := common.MustClientSet().AppsV1().Deployments(namespace()).Create(context.TODO(), deployment, metav1.CreateOptions{})
+	Expect(err).ToNot(HaveOccurred(), "failed to create deployment %s", deployName)
+
+	env := DisconnectEnv{
+		PvcName:        pvcName,
+		DeploymentName: deployName,
+		Labels:         podLabels,
+		Mode:           common.VolumeModeFilesystem,
+	}
+
+	Eventually(env.currentPodName, "2m", "1s").ShouldNot(BeEmpty(), "deployment %s never scheduled a pod", deployName)
+	env.PodName = env.currentPodName()
+	waitForPodRunning(env.PodName)
+	env.NodeName = podNodeName(env.PodName)
+	return env
+}
+
+func replicas(n int32) *int32 {
+	return &n
+}
@@ -0,0 +1,319 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package lib provides the fio-pod-on-mayastor-PVC fixture and node
+// disconnect primitives (cordon, iptables blackhole, kubelet stop) shared by
+// the node_disconnect Ginkgo specs: replica pod removal, and node shutdown.
+package lib
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"time"
+
+	"e2e-basic/common"
+
+	. "github.com/onsi/gomega"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// DisconnectEnv is the fixture set up by Setup: a single fio pod mounting a
+// PVC provisioned from a mayastor StorageClass. The pod is not pinned to a
+// node; NodeName records the node it landed on, which is the node whose
+// mayastor pod the spec then disrupts.
+type DisconnectEnv struct {
+	PvcName  string
+	ScName   string
+	PodName  string
+	NodeName string
+	// Mode is the volume mode the fio fixture was provisioned with, and
+	// therefore which path IntegrityRunner targets.
+	Mode common.VolumeMode
+	// DeploymentName is set instead of a bare Pod by SetupDeployment/
+	// AttachDeployment, for specs that need the workload recreated after its
+	// pod is force-deleted (e.g. NodeShutdownTest's reschedule assertion).
+	DeploymentName string
+	// Labels selects the pod currently owned by DeploymentName; empty for a
+	// bare-Pod env, where PodName never changes.
+	Labels map[string]string
+}
+
+// Setup provisions pvcName against scName and starts a pod, named imageName,
+// running imageName which mounts it in filesystem mode, waiting for the pod
+// to become Running and recording which node it landed on. Callers that want
+// an integrity check should call IntegrityRunner().WriteBaseline() before
+// injecting a fault. Use SetupWithMode for a raw block-mode PVC.
+func Setup(pvcName string, scName string, imageName string) DisconnectEnv {
+	return SetupWithMode(pvcName, scName, imageName, common.VolumeModeFilesystem)
+}
+
+// SetupWithMode is Setup with an explicit VolumeMode, for specs that need to
+// exercise the raw block-device data path rather than a mounted filesystem.
+func SetupWithMode(pvcName string, scName string, imageName string, mode common.VolumeMode) DisconnectEnv {
+	return SetupNamed(pvcName, scName, imageName, imageName, mode)
+}
+
+// SetupNamed is Setup with podName threaded separately from imageName, for
+// callers (such as snapshot_stress) that provision many concurrent fixtures
+// off the same fio image under unique pod names; Setup itself uses imageName
+// for both since it only ever creates one fixture per spec.
+func SetupNamed(pvcName string, scName string, podName string, imageName string, mode common.VolumeMode) DisconnectEnv {
+	volumeMode := v1.PersistentVolumeFilesystem
+	if mode == common.VolumeModeBlock {
+		volumeMode = v1.PersistentVolumeBlock
+	}
+
+	pvc := &v1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: pvcName},
+		Spec: v1.PersistentVolumeClaimSpec{
+			StorageClassName: &scName,
+			AccessModes:      []v1.PersistentVolumeAccessMode{v1.ReadWriteOnce},
+			VolumeMode:       &volumeMode,
+			Resources: v1.ResourceRequirements{
+				Requests: v1.ResourceList{v1.ResourceStorage: resourceQuantity("1Gi")},
+			},
+		},
+	}
+	_, err := common.MustClientSet().CoreV1().PersistentVolumeClaims(namespace()).Create(context.TODO(), pvc, metav1.CreateOptions{})
+	Expect(err).ToNot(HaveOccurred(), "failed to create pvc %s", pvcName)
+
+	env := AttachPodWithMode(podName, pvcName, imageName, mode)
+	env.ScName = scName
+	return env
+}
+
+// AttachPod starts a pod named podName running imageName against an
+// already-provisioned filesystem-mode pvcName, waiting for it to become
+// Running and recording which node it landed on. Used directly by specs
+// (such as snapshot_stress) that provision the PVC themselves, e.g.
+// restoring one from a VolumeSnapshot rather than from a StorageClass.
+func AttachPod(podName string, pvcName string, imageName string) DisconnectEnv {
+	return AttachPodWithMode(podName, pvcName, imageName, common.VolumeModeFilesystem)
+}
+
+// AttachPodWithMode is AttachPod with an explicit VolumeMode, matching
+// whatever mode pvcName was itself provisioned with.
+func AttachPodWithMode(podName string, pvcName string, imageName string, mode common.VolumeMode) DisconnectEnv {
+	pod := fioPod(podName, pvcName, imageName, mode)
+	_, err := common.MustClientSet().CoreV1().Pods(namespace()).Create(context.TODO(), pod, metav1.CreateOptions{})
+	Expect(err).ToNot(HaveOccurred(), "failed to create pod %s", podName)
+
+	waitForPodRunning(podName)
+
+	return DisconnectEnv{
+		PvcName:  pvcName,
+		PodName:  podName,
+		NodeName: podNodeName(podName),
+		Mode:     mode,
+	}
+}
+
+// IntegrityRunner returns the FioIntegrityRunner that writes/verifies data
+// against env's fio pod.
+func (env *DisconnectEnv) IntegrityRunner() *common.FioIntegrityRunner {
+	return &common.FioIntegrityRunner{
+		PodName:   env.PodName,
+		Namespace: namespace(),
+		Mode:      env.Mode,
+	}
+}
+
+// Teardown removes the fio workload (Deployment, if the env is backed by
+// one, otherwise the bare pod) and its PVC.
+func (env *DisconnectEnv) Teardown() {
+	if env.DeploymentName != "" {
+		_ = common.MustClientSet().AppsV1().Deployments(namespace()).Delete(context.TODO(), env.DeploymentName, metav1.DeleteOptions{})
+	} else {
+		_ = common.MustClientSet().CoreV1().Pods(namespace()).Delete(context.TODO(), env.PodName, metav1.DeleteOptions{})
+	}
+	_ = common.MustClientSet().CoreV1().PersistentVolumeClaims(namespace()).Delete(context.TODO(), env.PvcName, metav1.DeleteOptions{})
+}
+
+// SuppressMayastorPod simulates the node hosting the mayastor replica pod
+// going away: cordon the node, iptables-blackhole its traffic and stop its
+// kubelet, without formally telling the control plane the node is gone.
+func (env *DisconnectEnv) SuppressMayastorPod() {
+	cordonNode(env.NodeName)
+	blackholeNode(env.NodeName)
+	stopKubelet(env.NodeName)
+}
+
+// UnsuppressMayastorPod reverses SuppressMayastorPod so AfterSuite can leave
+// the cluster in a clean state even if the spec itself failed part-way.
+func (env *DisconnectEnv) UnsuppressMayastorPod() {
+	startKubelet(env.NodeName)
+	unblackholeNode(env.NodeName)
+	uncordonNode(env.NodeName)
+}
+
+// PodLossTest suppresses the mayastor pod's node for a while and asserts
+// both that the fio workload keeps running against the nexus once the
+// replica resyncs, and that the data written before the disconnect survived
+// byte-for-byte. Verify runs only once the node is reachable again: it execs
+// into the fio pod over kubectl, which SuppressMayastorPod's iptables
+// blackhole and stopped kubelet would otherwise make impossible to reach,
+// failing the exec rather than exercising the data path it's meant to check.
+func (env *DisconnectEnv) PodLossTest() {
+	env.IntegrityRunner().WriteBaseline()
+
+	func() {
+		env.SuppressMayastorPod()
+		defer env.UnsuppressMayastorPod()
+
+		time.Sleep(podLossObservationWindow)
+
+		Expect(podPhase(env.PodName)).To(Equal(v1.PodRunning), "fio pod did not survive mayastor node disconnect")
+	}()
+
+	env.IntegrityRunner().Verify()
+}
+
+// namespace is the namespace every node_disconnect fixture runs in.
+func namespace() string {
+	return "mayastor"
+}
+
+// podLossObservationWindow is how long PodLossTest waits, with the mayastor
+// node unreachable, before asserting the workload is still running.
+const podLossObservationWindow = 60 * time.Second
+
+// fioPod builds a pod that keeps its fio-capable container idling so
+// IntegrityRunner can drive deterministic write/verify cycles into it via
+// kubectl exec around whatever fault the spec injects, rather than the
+// container running a single fio pass at startup.
+func fioPod(name string, pvcName string, image string, mode common.VolumeMode) *v1.Pod {
+	container := v1.Container{
+		Name:  name,
+		Image: image,
+		Args:  []string{"sh", "-c", "sleep infinity"},
+	}
+
+	if mode == common.VolumeModeBlock {
+		container.VolumeDevices = []v1.VolumeDevice{
+			{Name: "volume", DevicePath: common.BlockDevicePath},
+		}
+	} else {
+		container.VolumeMounts = []v1.VolumeMount{
+			{Name: "volume", MountPath: "/volume"},
+		}
+	}
+
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: v1.PodSpec{
+			Containers: []v1.Container{container},
+			Volumes: []v1.Volume{
+				{
+					Name: "volume",
+					VolumeSource: v1.VolumeSource{
+						PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: pvcName},
+					},
+				},
+			},
+			RestartPolicy: v1.RestartPolicyNever,
+		},
+	}
+}
+
+func waitForPodRunning(name string) {
+	Eventually(func() v1.PodPhase {
+		return podPhase(name)
+	}, "2m", "1s").Should(Equal(v1.PodRunning))
+}
+
+func podPhase(name string) v1.PodPhase {
+	pod, err := common.MustClientSet().CoreV1().Pods(namespace()).Get(context.TODO(), name, metav1.GetOptions{})
+	Expect(err).ToNot(HaveOccurred(), "failed to get pod %s", name)
+	return pod.Status.Phase
+}
+
+func podNodeName(name string) string {
+	pod, err := common.MustClientSet().CoreV1().Pods(namespace()).Get(context.TODO(), name, metav1.GetOptions{})
+	Expect(err).ToNot(HaveOccurred(), "failed to get pod %s", name)
+	return pod.Spec.NodeName
+}
+
+// currentPodName returns the name of the pod env's Labels currently select,
+// or "" if the Deployment has no live pod yet (e.g. right after its old pod
+// was force-deleted and before the controller has recreated it). For a
+// bare-Pod env (Labels unset) it just returns PodName, which never changes.
+func (env *DisconnectEnv) currentPodName() string {
+	if len(env.Labels) == 0 {
+		return env.PodName
+	}
+
+	pods, err := common.MustClientSet().CoreV1().Pods(namespace()).List(context.TODO(), metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(env.Labels).String(),
+	})
+	Expect(err).ToNot(HaveOccurred(), "failed to list pods for %v", env.Labels)
+
+	for _, pod := range pods.Items {
+		if pod.DeletionTimestamp == nil {
+			return pod.Name
+		}
+	}
+	return ""
+}
+
+// cordonNode, uncordonNode, blackholeNode, unblackholeNode, stopKubelet and
+// startKubelet all shell out to kubectl (node commands through kubectl
+// debug), mirroring how the rest of the
+// e2e suite drives the cluster under test rather than talking to it purely
+// through client-go.
+
+func cordonNode(node string) {
+	runKubectl("cordon", node)
+}
+
+func uncordonNode(node string) {
+	runKubectl("uncordon", node)
+}
+
+// blackholeNode drops all traffic to/from node via an iptables rule injected
+// through a debug pod on that node, simulating a hard power-off without
+// actually taking the VM down.
+func blackholeNode(node string) {
+	runOnNode(node, "iptables -I INPUT 1 -j DROP && iptables -I OUTPUT 1 -j DROP")
+}
+
+func unblackholeNode(node string) {
+	runOnNode(node, "iptables -D INPUT -j DROP; iptables -D OUTPUT -j DROP")
+}
+
+func stopKubelet(node string) {
+	runOnNode(node, "systemctl stop kubelet")
+}
+
+func startKubelet(node string) {
+	runOnNode(node, "systemctl start kubelet")
+}
+
+func runOnNode(node string, shellCmd string) {
+	runKubectl("debug", fmt.Sprintf("node/%s", node), "-it", "--", "chroot", "/host", "sh", "-c", shellCmd)
+}
+
+func runKubectl(args ...string) {
+	out, err := exec.Command("kubectl", args...).CombinedOutput()
+	Expect(err).ToNot(HaveOccurred(), "kubectl %v failed: %s", args, string(out))
+}
+
+func resourceQuantity(s string) resource.Quantity {
+	q, err := resource.ParseQuantity(s)
+	Expect(err).ToNot(HaveOccurred())
+	return q
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lib
+
+import (
+	"context"
+	"time"
+
+	"e2e-basic/common"
+
+	. "github.com/onsi/gomega"
+
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// outOfServiceTaintKey is the key of the taint a cluster operator applies to a node that is
+// confirmed hard-powered-off, per the non-graceful node shutdown contract:
+// https://kubernetes.io/docs/concepts/architecture/nodes/#node-shutdowns
+const outOfServiceTaintKey = "node.kubernetes.io/out-of-service"
+
+// shutdownRescheduleWindow bounds how long NodeShutdownTest waits for the
+// workload to be force-deleted off the dead node, its VolumeAttachment
+// removed, and the pod rescheduled with the nexus rebuilt from the
+// surviving replicas.
+const shutdownRescheduleWindow = 3 * time.Minute
+
+// NodeShutdownTest hard-powers-off the node the fio pod and mayastor replica
+// are running on (cordon + iptables blackhole + kubelet stop, the same
+// primitives PodLossTest uses), applies the out-of-service taint and asserts
+// the pod is force-deleted, its VolumeAttachment removed and the workload
+// rescheduled onto a surviving node with the nexus rebuilt within
+// shutdownRescheduleWindow, with the data written before the shutdown intact.
+func (env *DisconnectEnv) NodeShutdownTest() {
+	deadNode := env.NodeName
+
+	env.IntegrityRunner().WriteBaseline()
+
+	env.SuppressMayastorPod()
+	defer env.UnsuppressMayastorPod()
+
+	applyOutOfServiceTaint(deadNode)
+	defer removeOutOfServiceTaint(deadNode)
+
+	Eventually(func() bool {
+		podName := env.currentPodName()
+		return podName != "" && podNodeName(podName) != "" && podNodeName(podName) != deadNode && podPhase(podName) == v1.PodRunning
+	}, shutdownRescheduleWindow, "5s").Should(BeTrue(),
+		"pod was not force-deleted and rescheduled off the shutdown node %s within %s", deadNode, shutdownRescheduleWindow)
+
+	Expect(volumeAttachmentExists(deadNode, env.PvcName)).To(BeFalse(),
+		"VolumeAttachment for %s on shutdown node %s was not removed", env.PvcName, deadNode)
+
+	env.PodName = env.currentPodName()
+	env.NodeName = podNodeName(env.PodName)
+	env.IntegrityRunner().Verify()
+}
+
+// NodeShutdownWithoutTaintTest is the control case for NodeShutdownTest: the
+// node is hard-powered-off but the out-of-service taint is never applied.
+// It deletes the pod itself (nothing else would, with the node otherwise
+// left untainted) and asserts the pod stays stuck Terminating and the volume
+// stays attached, proving the driver only force-detaches once the cluster
+// operator has confirmed the node is actually gone.
+func (env *DisconnectEnv) NodeShutdownWithoutTaintTest() {
+	deadNode := env.NodeName
+
+	env.SuppressMayastorPod()
+	defer env.UnsuppressMayastorPod()
+
+	deletePod(env.PodName)
+
+	Consistently(func() bool {
+		return podTerminating(env.PodName)
+	}, shutdownRescheduleWindow, "5s").Should(BeTrue(),
+		"pod %s did not stay Terminating without the out-of-service taint", env.PodName)
+
+	Consistently(func() bool {
+		return volumeAttachmentExists(deadNode, env.PvcName)
+	}, shutdownRescheduleWindow, "5s").Should(BeTrue(),
+		"VolumeAttachment for %s was removed without the out-of-service taint", env.PvcName)
+}
+
+// deletePod issues a normal (non-force) delete of name, the trigger that
+// puts it into the stuck-Terminating state NodeShutdownWithoutTaintTest
+// asserts on: with its node unreachable, kubelet can never confirm the
+// containers exited, and without the out-of-service taint the attach-detach
+// controller won't force-detach the volume to let the delete complete.
+func deletePod(name string) {
+	err := common.MustClientSet().CoreV1().Pods(namespace()).Delete(context.TODO(), name, metav1.DeleteOptions{})
+	Expect(err).ToNot(HaveOccurred(), "failed to delete pod %s", name)
+}
+
+// podTerminating reports whether name still exists but has been marked for
+// deletion, the state a pod is stuck in when its node is unreachable but,
+// absent the out-of-service taint, the attach-detach controller refuses to
+// force-detach its volume so the delete can complete.
+func podTerminating(name string) bool {
+	pod, err := common.MustClientSet().CoreV1().Pods(namespace()).Get(context.TODO(), name, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return false
+	}
+	Expect(err).ToNot(HaveOccurred(), "failed to get pod %s", name)
+	return pod.DeletionTimestamp != nil
+}
+
+func applyOutOfServiceTaint(node string) {
+	runKubectl("taint", "node", node, outOfServiceTaintKey+"=nodeshutdown:NoExecute")
+}
+
+func removeOutOfServiceTaint(node string) {
+	runKubectl("taint", "node", node, outOfServiceTaintKey+"=nodeshutdown:NoExecute-")
+}
+
+// volumeAttachmentExists reports whether a VolumeAttachment still binds pvcName
+// to node, looking the PV up via the PVC since VolumeAttachments are keyed by
+// PV name rather than PVC name.
+func volumeAttachmentExists(node string, pvcName string) bool {
+	pvc, err := common.MustClientSet().CoreV1().PersistentVolumeClaims("mayastor").Get(context.TODO(), pvcName, metav1.GetOptions{})
+	Expect(err).ToNot(HaveOccurred(), "failed to get pvc %s", pvcName)
+
+	vas, err := common.MustClientSet().StorageV1().VolumeAttachments().List(context.TODO(), metav1.ListOptions{})
+	Expect(err).ToNot(HaveOccurred(), "failed to list volume attachments")
+
+	for _, va := range vas.Items {
+		if va.Spec.NodeName == node && va.Spec.Source.PersistentVolumeName != nil && *va.Spec.Source.PersistentVolumeName == pvc.Spec.VolumeName {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package node_shutdown_test
+
+import (
+	"e2e-basic/common"
+	disconnect_lib "e2e-basic/node_disconnect/lib"
+
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+var env disconnect_lib.DisconnectEnv
+
+const gStorageClass = "mayastor-nvmf-3"
+
+func TestMayastorNodeShutdown(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Non-graceful node shutdown tests")
+}
+
+var _ = Describe("Mayastor non-graceful node shutdown test", func() {
+
+	It("should define the storage class to use", func() {
+		common.MkStorageClass(gStorageClass, 3, "nvmf", "io.openebs.csi-mayastor")
+	})
+
+	It("should force-detach and reschedule once the out-of-service taint is applied", func() {
+		env = disconnect_lib.SetupDeployment("shutdown-test-pvc-nvmf", gStorageClass, "fio")
+		env.NodeShutdownTest()
+		env.Teardown()
+	})
+
+	It("should leave the pod Terminating and the volume attached without the taint", func() {
+		env = disconnect_lib.Setup("shutdown-test-pvc-nvmf-no-taint", gStorageClass, "fio")
+		env.NodeShutdownWithoutTaintTest()
+		env.Teardown()
+	})
+})
+
+var _ = BeforeSuite(func(done Done) {
+	logf.SetLogger(zap.LoggerTo(GinkgoWriter, true))
+	common.SetupTestEnv()
+	close(done)
+}, 60)
+
+var _ = AfterSuite(func() {
+	By("tearing down the test environment")
+
+	env.UnsuppressMayastorPod()
+
+	common.RmStorageClass(gStorageClass)
+	common.TeardownTestEnv()
+})
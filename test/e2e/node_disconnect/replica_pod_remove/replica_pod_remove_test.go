@@ -25,7 +25,10 @@ func TestMayastorPodLoss(t *testing.T) {
 var _ = Describe("Mayastor replica pod removal test", func() {
 
 	It("should define the storage class to use", func() {
-		common.MkStorageClass(gStorageClass, 3, "nvmf", "io.openebs.csi-mayastor")
+		common.NewStorageClassBuilder(gStorageClass, common.MayastorProvisioner).
+			WithReplicas(3).
+			WithProtocol("nvmf").
+			Create()
 	})
 
 	It("should verify nvmf nexus behaviour when a mayastor pod is removed", func() {
@@ -0,0 +1,110 @@
+// Package provisioning generates a matrix of dynamic-provisioning sub-tests
+// over StorageClass variants (reclaim policy, binding mode, volume
+// expansion, repl/protocol parameters), the way the upstream k8s storage
+// testsuites parameterize their own provisioning tests, so a single run
+// covers dozens of mayastor SC combinations instead of the one fixed shape
+// MkStorageClass used to exercise. Every case leaves AllowedTopologies unset;
+// the matrix does not vary topology restriction.
+package provisioning_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"e2e-basic/common"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+func TestMayastorStorageClassMatrix(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "StorageClass provisioning matrix tests")
+}
+
+// storageClassCase is one point in the provisioning matrix.
+type storageClassCase struct {
+	name                 string
+	replicas             int
+	protocol             string
+	reclaimPolicy        corev1.PersistentVolumeReclaimPolicy
+	bindingMode          storagev1.VolumeBindingMode
+	allowVolumeExpansion bool
+}
+
+// storageClassMatrix is the cross product of repl/protocol/reclaim/binding/
+// expansion variants each provisioning sub-test exercises.
+func storageClassMatrix() []storageClassCase {
+	var cases []storageClassCase
+	for _, replicas := range []int{1, 3} {
+		for _, protocol := range []string{"nvmf", "iscsi"} {
+			for _, reclaimPolicy := range []corev1.PersistentVolumeReclaimPolicy{corev1.PersistentVolumeReclaimDelete, corev1.PersistentVolumeReclaimRetain} {
+				for _, bindingMode := range []storagev1.VolumeBindingMode{storagev1.VolumeBindingImmediate, storagev1.VolumeBindingWaitForFirstConsumer} {
+					for _, allowExpansion := range []bool{false, true} {
+						cases = append(cases, storageClassCase{
+							// StorageClass/PVC names must be lowercase RFC-1123, so the
+							// reclaim policy ("Delete"/"Retain") and binding mode
+							// ("Immediate"/"WaitForFirstConsumer") tokens are lower-cased.
+							name: strings.ToLower(fmt.Sprintf("repl%d-%s-%s-%s-expand%t", replicas, protocol, reclaimPolicy, bindingMode, allowExpansion)),
+							replicas:             replicas,
+							protocol:             protocol,
+							reclaimPolicy:        reclaimPolicy,
+							bindingMode:          bindingMode,
+							allowVolumeExpansion: allowExpansion,
+						})
+					}
+				}
+			}
+		}
+	}
+	return cases
+}
+
+var _ = Describe("Mayastor StorageClass provisioning matrix", func() {
+	for _, tc := range storageClassMatrix() {
+		tc := tc
+		It(fmt.Sprintf("should dynamically provision a PVC for %s", tc.name), func() {
+			scName := "matrix-" + tc.name
+			pvcName := "matrix-pvc-" + tc.name
+
+			common.NewStorageClassBuilder(scName, common.MayastorProvisioner).
+				WithReplicas(tc.replicas).
+				WithProtocol(tc.protocol).
+				WithReclaimPolicy(tc.reclaimPolicy).
+				WithBindingMode(tc.bindingMode).
+				WithVolumeExpansion(tc.allowVolumeExpansion).
+				Create()
+			defer common.RmStorageClass(scName)
+
+			common.MkPvc(pvcName, scName, "1Gi", "mayastor")
+			defer common.RmPvc(pvcName, "mayastor")
+
+			if tc.bindingMode == storagev1.VolumeBindingImmediate {
+				Eventually(func() corev1.PersistentVolumeClaimPhase {
+					return common.PvcPhase(pvcName, "mayastor")
+				}, "2m", "1s").Should(Equal(corev1.ClaimBound), "pvc %s never bound", pvcName)
+			} else {
+				Consistently(func() corev1.PersistentVolumeClaimPhase {
+					return common.PvcPhase(pvcName, "mayastor")
+				}, "10s", "1s").Should(Equal(corev1.ClaimPending), "WaitForFirstConsumer pvc %s bound without a consumer", pvcName)
+			}
+		})
+	}
+})
+
+var _ = BeforeSuite(func(done Done) {
+	logf.SetLogger(zap.LoggerTo(GinkgoWriter, true))
+	common.SetupTestEnv()
+	close(done)
+}, 60)
+
+var _ = AfterSuite(func() {
+	common.TeardownTestEnv()
+})
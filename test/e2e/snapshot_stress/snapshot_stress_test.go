@@ -0,0 +1,64 @@
+package snapshot_stress_test
+
+import (
+	"e2e-basic/common"
+	disconnect_lib "e2e-basic/node_disconnect/lib"
+	"e2e-basic/snapshot_stress"
+
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+const (
+	gStorageClass  = "mayastor-nvmf-1"
+	gSnapshotClass = "mayastor-snapshot"
+	gNamespace     = "mayastor"
+)
+
+func TestMayastorVolumeSnapshotStress(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Volume snapshot stress tests")
+}
+
+var _ = Describe("Mayastor volume snapshot stress test", func() {
+
+	It("should define the storage class and volume snapshot class to use", func() {
+		common.MkStorageClass(gStorageClass, 1, "nvmf", "io.openebs.csi-mayastor")
+		common.MkVolumeSnapshotClass(gSnapshotClass)
+	})
+
+	It("should survive a replica pod restart while snapshotting and restoring many PVCs concurrently", func() {
+		opts := snapshot_stress.VolumeSnapshotStressTestOptions{
+			NumPods:       5,
+			NumSnapshots:  3,
+			StorageClass:  gStorageClass,
+			SnapshotClass: gSnapshotClass,
+			Namespace:     gNamespace,
+		}
+
+		env := disconnect_lib.SetupNamed("snap-stress-disrupt-pvc", gStorageClass, "disrupt-fio", "fio", common.VolumeModeFilesystem)
+		defer env.Teardown()
+
+		hist := snapshot_stress.RunVolumeSnapshotStressTest(opts, &env)
+		hist.Report()
+	})
+})
+
+var _ = BeforeSuite(func(done Done) {
+	logf.SetLogger(zap.LoggerTo(GinkgoWriter, true))
+	common.SetupTestEnv()
+	close(done)
+}, 60)
+
+var _ = AfterSuite(func() {
+	By("tearing down the test environment")
+
+	common.RmVolumeSnapshotClass(gSnapshotClass)
+	common.RmStorageClass(gStorageClass)
+	common.TeardownTestEnv()
+})
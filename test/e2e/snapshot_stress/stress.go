@@ -0,0 +1,165 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package snapshot_stress concurrently provisions PVCs from the mayastor
+// StorageClass, snapshots and restores each one repeatedly, and verifies the
+// restored data with fio checksums while replica pods are bounced mid-run.
+package snapshot_stress
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"e2e-basic/common"
+	disconnect_lib "e2e-basic/node_disconnect/lib"
+
+	. "github.com/onsi/gomega"
+)
+
+// fioImage is the image every fixture's container runs, matching the image
+// name the other node_disconnect specs pass to disconnect_lib.Setup; unlike
+// those specs, snapshot_stress runs many concurrently-named fixtures off of
+// it rather than one pod actually named "fio".
+const fioImage = "fio"
+
+// VolumeSnapshotStressTestOptions configures a single RunVolumeSnapshotStressTest
+// invocation: how many PVCs to provision in parallel, how many snapshot/
+// restore/verify cycles to run against each, and which StorageClass/
+// VolumeSnapshotClass to provision and snapshot through.
+type VolumeSnapshotStressTestOptions struct {
+	// NumPods is the number of PVC+pod fixtures provisioned and driven
+	// concurrently.
+	NumPods int
+	// NumSnapshots is the number of snapshot/restore/verify cycles run
+	// against each fixture.
+	NumSnapshots int
+	StorageClass  string
+	SnapshotClass string
+	// Namespace is the namespace fixtures are created in.
+	Namespace string
+}
+
+// latencyHistogram accumulates per-operation durations so the suite can
+// report where time went (provision/snapshot/restore/verify) instead of just
+// a pass/fail.
+type latencyHistogram struct {
+	mu      sync.Mutex
+	samples map[string][]time.Duration
+}
+
+func newLatencyHistogram() *latencyHistogram {
+	return &latencyHistogram{samples: make(map[string][]time.Duration)}
+}
+
+func (h *latencyHistogram) record(op string, d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.samples[op] = append(h.samples[op], d)
+}
+
+// Report logs count/min/max/avg for every operation recorded, so a CI run
+// surfaces latency regressions even when every check passed.
+func (h *latencyHistogram) Report() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for op, samples := range h.samples {
+		min, max, total := samples[0], samples[0], time.Duration(0)
+		for _, d := range samples {
+			if d < min {
+				min = d
+			}
+			if d > max {
+				max = d
+			}
+			total += d
+		}
+		fmt.Printf("snapshot_stress: %-10s count=%-4d min=%-10s max=%-10s avg=%s\n",
+			op, len(samples), min, max, total/time.Duration(len(samples)))
+	}
+}
+
+// RunVolumeSnapshotStressTest drives opts.NumPods fixtures concurrently,
+// each through opts.NumSnapshots snapshot/restore/verify cycles, tolerating
+// the mayastor replica pod restart it injects mid-run by suppressing and
+// unsuppressing disrupt's node (no restart is injected when disrupt is nil).
+func RunVolumeSnapshotStressTest(opts VolumeSnapshotStressTestOptions, disrupt *disconnect_lib.DisconnectEnv) *latencyHistogram {
+	hist := newLatencyHistogram()
+
+	var wg sync.WaitGroup
+	for i := 0; i < opts.NumPods; i++ {
+		wg.Add(1)
+		go func(podIdx int) {
+			defer wg.Done()
+			defer GinkgoRecover()
+			runStressFixture(opts, podIdx, hist)
+		}(i)
+	}
+
+	// Bounce the mayastor replica pod partway through the run so restores
+	// and verifications have to survive a mid-flight replica resync. Joined
+	// via wg like the fixtures, so the caller's deferred Teardown can never
+	// run while this is still mid-disruption and leave the node suppressed
+	// past the spec.
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer GinkgoRecover()
+		if disrupt == nil {
+			return
+		}
+		time.Sleep(time.Duration(opts.NumSnapshots) * time.Second)
+		disrupt.SuppressMayastorPod()
+		time.Sleep(10 * time.Second)
+		disrupt.UnsuppressMayastorPod()
+	}()
+
+	wg.Wait()
+	return hist
+}
+
+func runStressFixture(opts VolumeSnapshotStressTestOptions, podIdx int, hist *latencyHistogram) {
+	pvcName := fmt.Sprintf("snap-stress-pvc-%d", podIdx)
+	podName := fmt.Sprintf("snap-stress-fio-%d", podIdx)
+
+	start := time.Now()
+	env := disconnect_lib.SetupNamed(pvcName, opts.StorageClass, podName, fioImage, common.VolumeModeFilesystem)
+	env.IntegrityRunner().WriteBaseline()
+	hist.record("provision", time.Since(start))
+	defer env.Teardown()
+
+	for snapIdx := 0; snapIdx < opts.NumSnapshots; snapIdx++ {
+		snapName := fmt.Sprintf("%s-snap-%d", pvcName, snapIdx)
+		restoreName := fmt.Sprintf("%s-restore-%d", pvcName, snapIdx)
+		restorePodName := fmt.Sprintf("%s-restore-fio-%d", podName, snapIdx)
+
+		start = time.Now()
+		common.MkVolumeSnapshot(snapName, pvcName, opts.SnapshotClass, opts.Namespace)
+		Eventually(func() bool {
+			return common.VolumeSnapshotReady(snapName, opts.Namespace)
+		}, "2m", "2s").Should(BeTrue(), "snapshot %s never became ready", snapName)
+		hist.record("snapshot", time.Since(start))
+
+		start = time.Now()
+		common.MkPvcFromSnapshot(restoreName, opts.StorageClass, snapName, "1Gi", opts.Namespace)
+		restoreEnv := disconnect_lib.AttachPod(restorePodName, restoreName, fioImage)
+		hist.record("restore", time.Since(start))
+
+		start = time.Now()
+		restoreEnv.IntegrityRunner().Verify()
+		hist.record("verify", time.Since(start))
+
+		restoreEnv.Teardown()
+		common.RmVolumeSnapshot(snapName, opts.Namespace)
+	}
+}